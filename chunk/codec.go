@@ -0,0 +1,115 @@
+/*
+ * Copyright 2019 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package chunk
+
+import (
+	"bytes"
+	"compress/bzip2"
+	"io"
+	"io/ioutil"
+	"sync"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// codec describes a non-gzip compression format that chunk.newReader can transparently
+// decompress, detected either by the magic bytes at the start of the file or, failing that, by
+// its file extension.
+type codec struct {
+	name    string
+	magic   []byte
+	ext     string
+	factory func(io.Reader) (io.ReadCloser, error)
+}
+
+var (
+	codecMu sync.RWMutex
+	codecs  []codec
+)
+
+// RegisterCodec adds a compression codec that chunk.NewReader (and NewReaderWithOptions) will
+// transparently decompress. magic is the byte sequence that identifies the format at the start of
+// a file; ext (including the leading dot, e.g. ".xz") is used as a fallback when no registered
+// magic bytes match; stdin has no extension, so it is only ever matched by magic. factory wraps a raw byte stream in a decompressing
+// io.ReadCloser; its Close is called, in addition to closing the underlying file, when the
+// chunk.Reader's cleanup function runs.
+//
+// RegisterCodec is meant to be called from an init function; it is safe for concurrent use but is
+// not intended to be churned at request time.
+func RegisterCodec(name string, magic []byte, ext string, factory func(io.Reader) (io.ReadCloser, error)) {
+	codecMu.Lock()
+	defer codecMu.Unlock()
+	codecs = append(codecs, codec{name: name, magic: magic, ext: ext, factory: factory})
+}
+
+// detectCodec returns the registered codec whose magic bytes prefix peek, or failing that, whose
+// extension matches ext. ok is false when nothing matches, meaning the input isn't compressed (or
+// uses a format nobody has registered a codec for).
+func detectCodec(ext string, peek []byte) (codec, bool) {
+	codecMu.RLock()
+	defer codecMu.RUnlock()
+
+	for _, c := range codecs {
+		if len(c.magic) > 0 && bytes.HasPrefix(peek, c.magic) {
+			return c, true
+		}
+	}
+	for _, c := range codecs {
+		if c.ext != "" && c.ext == ext {
+			return c, true
+		}
+	}
+	return codec{}, false
+}
+
+func init() {
+	RegisterCodec("bzip2", []byte("BZh"), ".bz2", func(r io.Reader) (io.ReadCloser, error) {
+		return ioutil.NopCloser(bzip2.NewReader(r)), nil
+	})
+
+	RegisterCodec("xz", []byte("\xFD7zXZ\x00"), ".xz", func(r io.Reader) (io.ReadCloser, error) {
+		xzr, err := xz.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return ioutil.NopCloser(xzr), nil
+	})
+
+	RegisterCodec("zstd", []byte("\x28\xB5\x2F\xFD"), ".zst", func(r io.Reader) (io.ReadCloser, error) {
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return &zstdReadCloser{zr}, nil
+	})
+
+	RegisterCodec("snappy", []byte("\xFF\x06\x00\x00sNaPpY"), ".sz", func(r io.Reader) (io.ReadCloser, error) {
+		return ioutil.NopCloser(snappy.NewReader(r)), nil
+	})
+}
+
+// zstdReadCloser adapts *zstd.Decoder, whose Close takes and returns nothing, to io.ReadCloser.
+type zstdReadCloser struct {
+	*zstd.Decoder
+}
+
+func (z *zstdReadCloser) Close() error {
+	z.Decoder.Close()
+	return nil
+}
@@ -0,0 +1,71 @@
+/*
+ * Copyright 2019 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package chunk
+
+import (
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// registerIdentityCodec registers a codec that round-trips its input unchanged; it exists purely so these tests can exercise
+// RegisterCodec/detectCodec/newReader's wiring without depending on a real bzip2/xz/zstd/snappy
+// sample blob.
+func registerIdentityCodec(t *testing.T, name, magic, ext string) {
+	t.Helper()
+	RegisterCodec(name, []byte(magic), ext, func(r io.Reader) (io.ReadCloser, error) {
+		return ioutil.NopCloser(r), nil
+	})
+}
+
+func TestDetectCodecByMagic(t *testing.T) {
+	registerIdentityCodec(t, "test-magic", "TESTMAGIC", ".testmagic")
+
+	c, ok := detectCodec(".bin", []byte("TESTMAGIC and then some payload bytes"))
+	require.True(t, ok)
+	require.Equal(t, "test-magic", c.name)
+}
+
+func TestDetectCodecFallsBackToExtension(t *testing.T) {
+	registerIdentityCodec(t, "test-ext", "", ".testext")
+
+	c, ok := detectCodec(".testext", []byte("no matching magic here"))
+	require.True(t, ok)
+	require.Equal(t, "test-ext", c.name)
+}
+
+func TestDetectCodecNoMatch(t *testing.T) {
+	_, ok := detectCodec(".unregistered-ext-xyz", []byte("plain text, not compressed"))
+	require.False(t, ok)
+}
+
+func TestNewReaderUsesRegisteredCodec(t *testing.T) {
+	registerIdentityCodec(t, "test-roundtrip", "", ".ident")
+
+	dir := t.TempDir()
+	path := writeFile(t, dir, "data.ident", []byte("hello via the codec registry"))
+
+	rd, cleanup := NewReaderWithOptions(path, ReaderOptions{Workers: 1})
+	defer cleanup()
+
+	got, err := ioutil.ReadAll(rd.rd)
+	require.NoError(t, err)
+	require.Equal(t, "hello via the codec registry", string(got))
+	require.True(t, rd.compressed)
+}
@@ -0,0 +1,268 @@
+/*
+ * Copyright 2019 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package chunk
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/golang/glog"
+
+	"github.com/dgraph-io/dgraph/x"
+)
+
+// ErrCoarseIndex is returned by BuildIndex when the input is (essentially) a single gzip member,
+// so the index it wrote can only resume decoding at offset 0 -- it cannot give the caller the
+// sub-member random access that's the point of indexing in the first place. The index is still
+// written and usable; NewIndexedReader logs a warning rather than treating this as fatal, but
+// callers that need true sharding of a single huge .rdf.gz should pre-split it into multiple gzip
+// members (see ReaderOptions.SingleStream / Reader.NextMember) before indexing it.
+var ErrCoarseIndex = x.Errorf("chunk: input has no gzip member boundaries past offset 0; " +
+	"index only supports seeking to the start, not true random access")
+
+// gzIndexEntry records a point the gzip stream can be safely resumed from: the file offset of a
+// gzip member header, and the uncompressed offset its first byte corresponds to. Note this is
+// deliberately *not* an arbitrary mid-member offset: compress/flate doesn't expose a byte-aligned
+// compressed-stream cursor, so the only points Go's stdlib lets us jump back into cleanly are
+// member boundaries, which need no dictionary priming at all (gzip.NewReader just starts fresh).
+// In practice this is exactly what the bulk loader needs: a large export pre-split into many
+// gzip members (one per shard, or periodically flushed), which is also the shape request #2's
+// SingleStream mode was added to consume.
+type gzIndexEntry struct {
+	CompressedOffset   int64
+	UncompressedOffset int64
+}
+
+type gzIndex struct {
+	Entries []gzIndexEntry
+}
+
+const gzIndexMagic = "DGZI"
+
+// BuildIndex scans file's gzip members once, writing an index to file+".gzi" that records the
+// (compressed offset, uncompressed offset) of every member whose start lies at least spanBytes of
+// uncompressed output past the previous entry. NewIndexedReader uses this index to seek directly
+// to the member containing a requested uncompressed offset instead of decoding from the start.
+//
+// Because seeking is only exact at member boundaries, spanBytes is a lower bound, not a fixed
+// stride: a concatenated-gzip input with large members will produce sparser entries than spanBytes
+// asks for. For the common bulk-loader shape -- many modest members, e.g. one per RDF shard --
+// this still gives useful random access. If the input produces no entry past offset 0 although
+// its uncompressed size exceeds spanBytes (e.g. one giant member), the index is still written, but
+// BuildIndex returns ErrCoarseIndex to make that loudly visible instead of silently shipping a
+// no-op index. An input no larger than spanBytes, including an empty one, returns nil.
+func BuildIndex(file string, spanBytes int64) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	idx := gzIndex{}
+	var uncompressed, sinceLastEntry int64
+	var compressedOffset int64
+
+	for {
+		if _, err := f.Seek(compressedOffset, io.SeekStart); err != nil {
+			return err
+		}
+		// gzip.NewReader reuses br as-is, without an extra internal buffering layer, because
+		// *bufio.Reader already satisfies the io.ByteReader interface it looks for. That's what
+		// lets us recover the exact compressed length of this member below via br.Buffered():
+		// with any plain io.Reader, gzip would wrap it in a bufio.Reader of its own and we'd have
+		// no way to tell how far past the member end it had spuriously read ahead.
+		cr := &countingReader{rd: f}
+		br := bufio.NewReader(cr)
+		gzr, err := gzip.NewReader(br)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		gzr.Multistream(false)
+
+		if sinceLastEntry >= spanBytes || len(idx.Entries) == 0 {
+			idx.Entries = append(idx.Entries, gzIndexEntry{
+				CompressedOffset:   compressedOffset,
+				UncompressedOffset: uncompressed,
+			})
+			sinceLastEntry = 0
+		}
+
+		n, err := io.Copy(ioutil.Discard, gzr)
+		gzr.Close()
+		if err != nil {
+			return err
+		}
+		uncompressed += n
+		sinceLastEntry += n
+		compressedOffset += cr.n - int64(br.Buffered())
+	}
+
+	if err := writeIndex(file+".gzi", &idx); err != nil {
+		return err
+	}
+	if len(idx.Entries) <= 1 && uncompressed > spanBytes {
+		return ErrCoarseIndex
+	}
+	return nil
+}
+
+// countingReader tracks how many bytes have been read off rd, which is how BuildIndex recovers
+// the compressed length of a member: compress/gzip never reports it directly.
+type countingReader struct {
+	rd io.Reader
+	n  int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.rd.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func writeIndex(path string, idx *gzIndex) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	if _, err := w.WriteString(gzIndexMagic); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.LittleEndian, int64(len(idx.Entries))); err != nil {
+		return err
+	}
+	for _, e := range idx.Entries {
+		if err := binary.Write(w, binary.LittleEndian, e.CompressedOffset); err != nil {
+			return err
+		}
+		if err := binary.Write(w, binary.LittleEndian, e.UncompressedOffset); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+func readIndex(path string) (*gzIndex, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	magic := make([]byte, len(gzIndexMagic))
+	if _, err := io.ReadFull(r, magic); err != nil {
+		return nil, err
+	}
+	if string(magic) != gzIndexMagic {
+		return nil, x.Errorf("chunk: %q is not a valid .gzi index file", path)
+	}
+
+	var count int64
+	if err := binary.Read(r, binary.LittleEndian, &count); err != nil {
+		return nil, err
+	}
+
+	idx := &gzIndex{Entries: make([]gzIndexEntry, count)}
+	for i := range idx.Entries {
+		e := &idx.Entries[i]
+		if err := binary.Read(r, binary.LittleEndian, &e.CompressedOffset); err != nil {
+			return nil, err
+		}
+		if err := binary.Read(r, binary.LittleEndian, &e.UncompressedOffset); err != nil {
+			return nil, err
+		}
+	}
+	return idx, nil
+}
+
+// NewIndexedReader opens file using its companion file+".gzi" index (building one with a 4MiB
+// span if it doesn't exist yet) and returns a Reader that additionally supports
+// SeekUncompressed, letting the caller jump to the gzip member covering an arbitrary uncompressed
+// byte offset instead of decoding every member before it.
+func NewIndexedReader(file string) (*Reader, func()) {
+	indexPath := file + ".gzi"
+	if _, err := os.Stat(indexPath); os.IsNotExist(err) {
+		if err := BuildIndex(file, 4<<20); err != nil {
+			if err != ErrCoarseIndex {
+				x.Check(err)
+			}
+			glog.Warningf("chunk: %s: %v; random-access seeks will fall back to sequential decode", file, err)
+		}
+	}
+	idx, err := readIndex(indexPath)
+	x.Check(err)
+
+	f, err := os.Open(file)
+	x.Check(err)
+
+	rd := &Reader{filename: file, compressed: true, gzIdx: idx, gzFile: f}
+	x.Check(rd.SeekUncompressed(0))
+
+	return rd, func() { f.Close() }
+}
+
+// SeekUncompressed repositions the Reader to the start of the gzip member at the nearest index
+// entry at or before uncompressed byte offset off, then decodes and discards the bytes from there
+// up to off, across member boundaries where the index is sparser than the members. It requires a
+// Reader opened with NewIndexedReader.
+func (r *Reader) SeekUncompressed(off int64) error {
+	if r.gzIdx == nil {
+		return x.Errorf("chunk: SeekUncompressed requires a Reader opened with NewIndexedReader")
+	}
+	if len(r.gzIdx.Entries) == 0 {
+		return x.Errorf("chunk: %s has an empty gzip index (no members found)", r.filename)
+	}
+
+	entry := r.gzIdx.Entries[0]
+	for _, e := range r.gzIdx.Entries {
+		if e.UncompressedOffset > off {
+			break
+		}
+		entry = e
+	}
+
+	if _, err := r.gzFile.Seek(entry.CompressedOffset, io.SeekStart); err != nil {
+		return err
+	}
+	gzr, err := gzip.NewReader(r.gzFile)
+	if err != nil {
+		return err
+	}
+
+	r.rd = bufio.NewReader(gzr)
+	r.offset = int(entry.UncompressedOffset)
+	r.line = 0 // line numbers aren't meaningful across a seek into the middle of a file
+
+	if toDiscard := off - entry.UncompressedOffset; toDiscard > 0 {
+		if _, err := io.CopyN(ioutil.Discard, r.rd, toDiscard); err != nil {
+			return err
+		}
+		r.offset += int(toDiscard)
+	}
+
+	return nil
+}
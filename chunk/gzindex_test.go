@@ -0,0 +1,94 @@
+/*
+ * Copyright 2019 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package chunk
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, dir, name string, data []byte) string {
+	path := filepath.Join(dir, name)
+	require.NoError(t, ioutil.WriteFile(path, data, 0o600))
+	return path
+}
+
+func TestBuildIndexEmptyFile(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, "empty.gz", nil)
+
+	require.NoError(t, BuildIndex(path, 1<<20))
+
+	idx, err := readIndex(path + ".gzi")
+	require.NoError(t, err)
+	require.Empty(t, idx.Entries)
+}
+
+func TestSeekUncompressedEmptyIndexReturnsError(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, "empty.gz", nil)
+
+	require.NoError(t, BuildIndex(path, 1<<20))
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	idx, err := readIndex(path + ".gzi")
+	require.NoError(t, err)
+
+	rd := &Reader{filename: path, gzIdx: idx, gzFile: f}
+	require.Error(t, rd.SeekUncompressed(0))
+}
+
+func TestBuildIndexSingleMemberIsCoarse(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, "one.gz", gzipMember(t, "one.rdf", "just one member, no boundaries to index"))
+
+	err := BuildIndex(path, 4)
+	require.Equal(t, ErrCoarseIndex, err)
+}
+
+func TestBuildIndexAndSeekMultiMember(t *testing.T) {
+	dir := t.TempDir()
+	var data []byte
+	data = append(data, gzipMember(t, "", "aaaaaaaaaa")...)
+	data = append(data, gzipMember(t, "", "bbbbbbbbbb")...)
+	data = append(data, gzipMember(t, "", "cccccccccc")...)
+	path := writeFile(t, dir, "shards.gz", data)
+
+	require.NoError(t, BuildIndex(path, 1))
+
+	idx, err := readIndex(path + ".gzi")
+	require.NoError(t, err)
+	require.Len(t, idx.Entries, 3)
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	rd := &Reader{filename: path, gzIdx: idx, gzFile: f}
+	require.NoError(t, rd.SeekUncompressed(20))
+
+	got, err := ioutil.ReadAll(rd.rd)
+	require.NoError(t, err)
+	require.Equal(t, "cccccccccc", string(got))
+}
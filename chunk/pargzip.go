@@ -0,0 +1,210 @@
+/*
+ * Copyright 2019 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package chunk
+
+import (
+	"compress/gzip"
+	"io"
+	"runtime"
+	"sync"
+)
+
+// ReaderOptions controls how NewReaderWithOptions decompresses gzip input.
+type ReaderOptions struct {
+	// Workers sizes the read-ahead pipeline: pargzipReader decodes on a single background
+	// goroutine (see the doc comment on pargzipReader for why more than one can't help on a
+	// single sequential gzip stream), but that goroutine runs concurrently with whatever the
+	// caller does with each decoded block, buffering up to Workers blocks ahead so the consumer
+	// isn't blocked waiting on the next inflate call. Workers <= 1 disables the background
+	// goroutine entirely and falls back to a plain, synchronous compress/gzip reader.
+	Workers int
+
+	// BlockSize is the size, in bytes, of the read-ahead buffer slots.
+	BlockSize int
+
+	// SingleStream, when true, stops the Reader at the end of the first gzip member instead of
+	// silently gluing every member in a concatenated .gz file into one logical stream. The caller
+	// can then inspect Reader.Headers() and call Reader.NextMember() to keep reading the file one
+	// member at a time. It is the opposite sense of compress/gzip's Multistream(true) default.
+	SingleStream bool
+}
+
+// DefaultReaderOptions returns the ReaderOptions used when NewReader is asked to decompress
+// gzip input: a GOMAXPROCS-deep read-ahead pipeline, with 1MB blocks.
+func DefaultReaderOptions() ReaderOptions {
+	return ReaderOptions{
+		Workers:   runtime.GOMAXPROCS(0),
+		BlockSize: 1 << 20,
+	}
+}
+
+// block is one slot of decompressed output flowing through the read-ahead pipeline, handed from
+// the decode goroutine to the bufio.Reader that drains it in order.
+type block struct {
+	data []byte
+	err  error
+}
+
+// pargzipReader decompresses a, possibly multi-member, gzip stream on a single background
+// goroutine, feeding decoded blocks through a bounded channel that the caller drains.
+//
+// This is deliberately NOT multiple goroutines inflating different parts of the stream at once:
+// compress/gzip can't random-access into the middle of a deflate stream (later blocks depend on
+// the bit-exact decode state of everything before them), and discovering where one gzip member
+// ends and the next begins requires decoding the first member in full, so there is no boundary to
+// hand to a second worker until the first worker has already done all the work of finding it.
+// Concurrently decoding independent *known* members of a seekable, pre-indexed file (see
+// NewIndexedReader) is a different, genuinely parallel problem; a single sequential pargzipReader
+// stream is not. What this type buys is overlap, not parallelism: the decode goroutine keeps
+// inflating into the next buffered block while the caller is still processing (or still blocked
+// reading off disk/network for) the previous one, which is a real, if more modest, speedup on a
+// multi-core machine than strictly alternating read/inflate/consume.
+type pargzipReader struct {
+	src  io.Reader
+	opts ReaderOptions
+	out  chan *block
+	done chan struct{}
+
+	mu      sync.Mutex
+	headers []gzip.Header
+
+	pending []byte // unread remainder of the block currently being drained
+}
+
+// Headers returns the gzip header of each member decoded so far. Safe to call concurrently with
+// Read, since the decode goroutine is still appending to it in the background.
+func (pr *pargzipReader) Headers() []gzip.Header {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+	return append([]gzip.Header(nil), pr.headers...)
+}
+
+func newPargzipReader(src io.Reader, opts ReaderOptions) *pargzipReader {
+	if opts.Workers < 1 {
+		opts.Workers = 1
+	}
+	if opts.BlockSize <= 0 {
+		opts.BlockSize = 1 << 20
+	}
+
+	pr := &pargzipReader{
+		src:  src,
+		opts: opts,
+		// Bound the ring buffer so a slow consumer applies backpressure instead of letting the
+		// decode goroutine race arbitrarily far ahead and balloon memory.
+		out:  make(chan *block, opts.Workers),
+		done: make(chan struct{}),
+	}
+	go pr.run()
+	return pr
+}
+
+// run decodes each gzip member in turn on pr's single background goroutine, pushing decompressed
+// bytes onto pr.out in order, and exits promptly once pr.done is closed.
+func (pr *pargzipReader) run() {
+	defer close(pr.out)
+
+	for {
+		select {
+		case <-pr.done:
+			return
+		default:
+		}
+
+		gzr, err := gzip.NewReader(pr.src)
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			select {
+			case pr.out <- &block{err: err}:
+			case <-pr.done:
+			}
+			return
+		}
+		// Without this, compress/gzip's own Multistream(true) default glues every subsequent
+		// member into this same gzr, so decodeMember never returns until the whole file is
+		// drained: the outer loop would never see a second member, and Headers() would
+		// permanently under-report.
+		gzr.Multistream(false)
+		pr.mu.Lock()
+		pr.headers = append(pr.headers, gzr.Header)
+		pr.mu.Unlock()
+
+		if !pr.decodeMember(gzr) {
+			return
+		}
+	}
+}
+
+// decodeMember reads gzr to completion, pushing each block onto pr.out. It returns false if
+// pr.done fired partway through, signalling run to stop looking for further members.
+func (pr *pargzipReader) decodeMember(gzr *gzip.Reader) bool {
+	defer gzr.Close()
+
+	for {
+		buf := make([]byte, pr.opts.BlockSize)
+		n, err := gzr.Read(buf)
+		if n > 0 {
+			select {
+			case pr.out <- &block{data: buf[:n]}:
+			case <-pr.done:
+				return false
+			}
+		}
+		if err == io.EOF {
+			return true
+		}
+		if err != nil {
+			select {
+			case pr.out <- &block{err: err}:
+			case <-pr.done:
+			}
+			return false
+		}
+	}
+}
+
+func (pr *pargzipReader) Read(p []byte) (int, error) {
+	if len(pr.pending) == 0 {
+		blk, ok := <-pr.out
+		if !ok {
+			return 0, io.EOF
+		}
+		if blk.err != nil {
+			return 0, blk.err
+		}
+		pr.pending = blk.data
+	}
+
+	n := copy(p, pr.pending)
+	pr.pending = pr.pending[n:]
+	return n, nil
+}
+
+// Close stops the decode goroutine and drains pr.out so it doesn't block forever trying to push a
+// final block, then returns. Safe to call multiple times.
+func (pr *pargzipReader) Close() error {
+	select {
+	case <-pr.done:
+	default:
+		close(pr.done)
+	}
+	for range pr.out {
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+/*
+ * Copyright 2019 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package chunk
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func gzipMember(t *testing.T, name, body string) []byte {
+	var buf bytes.Buffer
+	gw, err := gzip.NewWriterLevel(&buf, gzip.BestSpeed)
+	require.NoError(t, err)
+	gw.Name = name
+	_, err = gw.Write([]byte(body))
+	require.NoError(t, err)
+	require.NoError(t, gw.Close())
+	return buf.Bytes()
+}
+
+func TestPargzipReaderSingleMember(t *testing.T) {
+	want := strings.Repeat("the quick brown fox jumps over the lazy dog\n", 1000)
+	src := bytes.NewReader(gzipMember(t, "a.rdf", want))
+
+	pr := newPargzipReader(src, ReaderOptions{Workers: 4, BlockSize: 4096})
+	got, err := ioutil.ReadAll(pr)
+	require.NoError(t, err)
+	require.Equal(t, want, string(got))
+	require.Equal(t, []string{"a.rdf"}, headerNames(pr.Headers()))
+}
+
+func TestPargzipReaderMultiMember(t *testing.T) {
+	var src bytes.Buffer
+	src.Write(gzipMember(t, "shard-0.rdf", "first member\n"))
+	src.Write(gzipMember(t, "shard-1.rdf", "second member\n"))
+
+	pr := newPargzipReader(&src, ReaderOptions{Workers: 4, BlockSize: 4096})
+	got, err := ioutil.ReadAll(pr)
+	require.NoError(t, err)
+	require.Equal(t, "first member\nsecond member\n", string(got))
+	require.Equal(t, []string{"shard-0.rdf", "shard-1.rdf"}, headerNames(pr.Headers()))
+}
+
+func headerNames(hs []gzip.Header) []string {
+	names := make([]string, len(hs))
+	for i, h := range hs {
+		names[i] = h.Name
+	}
+	return names
+}
+
+// TestPargzipReaderCloseStopsPromptly guards against the decode goroutine grinding through the
+// rest of a multi-member stream after Close, instead of exiting as soon as it notices pr.done.
+func TestPargzipReaderCloseStopsPromptly(t *testing.T) {
+	var src bytes.Buffer
+	for i := 0; i < 100; i++ {
+		src.Write(gzipMember(t, "", strings.Repeat("x", 1<<16)))
+	}
+
+	pr := newPargzipReader(&src, ReaderOptions{Workers: 1, BlockSize: 1024})
+	buf := make([]byte, 1024)
+	_, err := pr.Read(buf)
+	require.NoError(t, err)
+
+	done := make(chan struct{})
+	go func() {
+		pr.Close()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Close did not return promptly after cancellation")
+	}
+}
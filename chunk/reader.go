@@ -40,30 +40,62 @@ type Reader struct {
 	// these are used to handle UnreadRune
 	prevOffset int
 	prevLine   int
+
+	// these support multi-member gzip streams; they are left zero for non-gzip input. In the
+	// default glued-multistream mode only opts and one of pgzr or msr are set; headers, file and
+	// src are used with SingleStream.
+	opts    ReaderOptions
+	headers []gzip.Header
+	pgzr    *pargzipReader
+	msr     *multistreamGzipReader
+	file    io.Closer // shared across every member Reader produced by NextMember
+	src     io.Reader // the still-compressed stream, positioned right after the current member
+
+	// these support NewIndexedReader / SeekUncompressed
+	gzIdx  *gzIndex
+	gzFile *os.File
 }
 
-// NewReader returns an open reader and cleanup function for the given file. Gzip-compressed input
-// is detected and decompressed automatically even without the gz extension. The caller is
-// responsible for calling the returned cleanup function when done with the reader.
+// NewReader returns an open reader and cleanup function for the given file. file may also be a
+// "-" for stdin, or an http://, https://, s3:// or gs:// URL to stream from object storage.
+// Gzip-compressed input is detected and decompressed automatically even without the gz extension.
+// The caller is responsible for calling the returned cleanup function when done with the reader.
 func NewReader(file string) (*Reader, func()) {
-	var f *os.File
+	return NewReaderWithOptions(file, DefaultReaderOptions())
+}
+
+// NewReaderWithOptions is like NewReader, but lets the caller control how gzip-compressed input
+// is decompressed. With opts.Workers > 1, decoding runs on one background goroutine that reads
+// up to opts.Workers blocks ahead of the caller (see pargzipReader); the stream is never inflated
+// by more than one goroutine at a time. opts.Workers <= 1 falls back to the plain compress/gzip
+// path.
+func NewReaderWithOptions(file string, opts ReaderOptions) (*Reader, func()) {
+	var f io.ReadCloser
+	var filename string
 	var err error
-	if file == "-" {
-		f, file = os.Stdin, "/dev/stdin"
-	} else {
+
+	switch {
+	case isURL(file):
+		f, filename = openURL(file)
+	case file == "-":
+		f, filename = os.Stdin, "/dev/stdin"
+	default:
 		f, err = os.Open(file)
+		filename = file
 	}
 	x.Check(err)
 
-	return newReader(f)
+	return newReader(f, filename, opts)
 }
 
-func newReader(f *os.File) (*Reader, func()) {
-	var rd = Reader{filename: f.Name()}
+func newReader(f io.ReadCloser, filename string, opts ReaderOptions) (*Reader, func()) {
+	var rd = Reader{filename: filename}
 	var cleanup = func() { f.Close() }
 
+	ext := filepath.Ext(rd.filename)
+
 	var gzf io.Reader
-	if filepath.Ext(rd.filename) == ".gz" {
+	if ext == ".gz" {
 		gzf = f
 	} else {
 		rd.rd = bufio.NewReader(f)
@@ -71,20 +103,166 @@ func newReader(f *os.File) (*Reader, func()) {
 		typ := http.DetectContentType(buf)
 		if typ == "application/x-gzip" {
 			gzf = rd.rd
+		} else if c, ok := detectCodec(ext, buf); ok {
+			cr, err := c.factory(rd.rd)
+			x.CheckfNoTrace(err)
+			rd.rd = bufio.NewReader(cr)
+			rd.compressed = true
+			cleanup = func() { cr.Close(); f.Close() }
+			return &rd, cleanup
 		}
 	}
 
 	if gzf != nil {
-		gzr, err := gzip.NewReader(gzf)
-		x.CheckfNoTrace(err)
-		rd.rd = bufio.NewReader(gzr)
+		rd.opts = opts
 		rd.compressed = true
-		cleanup = func() { f.Close(); gzr.Close() }
+		// gzip.NewReader wraps anything that isn't an io.ByteReader in a private buffer that reads
+		// past the end of the member; share one buffer so each member starts where the last ended.
+		if _, ok := gzf.(io.ByteReader); !ok {
+			gzf = bufio.NewReader(gzf)
+		}
+
+		switch {
+		case opts.SingleStream:
+			gzr, err := gzip.NewReader(gzf)
+			x.CheckfNoTrace(err)
+			gzr.Multistream(false)
+			rd.headers = append(rd.headers, gzr.Header)
+			rd.rd = bufio.NewReader(gzr)
+			rd.file, rd.src = f, gzf
+			cleanup = func() { gzr.Close(); f.Close() }
+		case opts.Workers > 1:
+			pgzr := newPargzipReader(gzf, opts)
+			rd.pgzr = pgzr
+			rd.rd = bufio.NewReader(pgzr)
+			cleanup = func() { pgzr.Close(); f.Close() }
+		default:
+			msr, err := newMultistreamGzipReader(gzf)
+			x.CheckfNoTrace(err)
+			rd.msr = msr
+			rd.rd = bufio.NewReader(msr)
+			cleanup = func() { f.Close(); msr.Close() }
+		}
 	}
 
 	return &rd, cleanup
 }
 
+// Headers returns the gzip header of each member read so far. It is empty for non-gzip input.
+func (r *Reader) Headers() []gzip.Header {
+	switch {
+	case r.pgzr != nil:
+		return r.pgzr.Headers()
+	case r.msr != nil:
+		return r.msr.Headers()
+	default:
+		return r.headers
+	}
+}
+
+// multistreamGzipReader glues together the members of a concatenated gzip stream, the same way
+// compress/gzip's own Multistream(true) default does, but -- unlike compress/gzip, which only
+// ever records the first member's Header -- keeps every member's header as it's encountered, so
+// Reader.Headers() reflects the whole file, not just the first member glued into it.
+type multistreamGzipReader struct {
+	src     io.Reader
+	cur     *gzip.Reader
+	headers []gzip.Header
+}
+
+func newMultistreamGzipReader(src io.Reader) (*multistreamGzipReader, error) {
+	r := &multistreamGzipReader{src: src}
+	if err := r.openNextMember(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *multistreamGzipReader) openNextMember() error {
+	gzr, err := gzip.NewReader(r.src)
+	if err != nil {
+		return err
+	}
+	gzr.Multistream(false)
+	r.cur = gzr
+	r.headers = append(r.headers, gzr.Header)
+	return nil
+}
+
+func (r *multistreamGzipReader) Headers() []gzip.Header {
+	return append([]gzip.Header(nil), r.headers...)
+}
+
+func (r *multistreamGzipReader) Read(p []byte) (int, error) {
+	for {
+		n, err := r.cur.Read(p)
+		if err == nil {
+			return n, nil
+		}
+		if err != io.EOF {
+			return n, err
+		}
+		// gzip.Reader commonly reports io.EOF together with the final bytes of a member, not on
+		// a separate, empty call. Hand those bytes back first and swallow the EOF -- only treat
+		// it as the end of the whole stream once no *next* member header can be read, otherwise
+		// a concatenated gzip file gets silently truncated to its first member.
+		if n > 0 {
+			return n, nil
+		}
+		r.cur.Close()
+		if err := r.openNextMember(); err != nil {
+			if err == io.EOF {
+				return 0, io.EOF
+			}
+			return 0, err
+		}
+	}
+}
+
+func (r *multistreamGzipReader) Close() error {
+	return r.cur.Close()
+}
+
+// NextMember advances past the end of the current gzip member and returns a Reader for the next
+// one, along with a cleanup function and true. It only applies when the original Reader was
+// opened with ReaderOptions.SingleStream; it returns ok == false once there are no more members.
+// The returned cleanup closes the shared underlying file, so it is safe (if redundant) to also
+// call the previous member's cleanup.
+func (r *Reader) NextMember() (next *Reader, cleanup func(), ok bool) {
+	if r.src == nil {
+		return nil, func() {}, false
+	}
+
+	// Skip whatever is still unread of the current member, so r.src sits at the next header.
+	for {
+		if _, err := r.rd.Discard(1 << 20); err != nil {
+			if err != io.EOF {
+				x.CheckfNoTrace(err)
+			}
+			break
+		}
+	}
+
+	gzr, err := gzip.NewReader(r.src)
+	if err == io.EOF {
+		return nil, func() {}, false
+	}
+	x.CheckfNoTrace(err)
+	gzr.Multistream(false)
+
+	nr := &Reader{
+		filename:   r.filename,
+		compressed: true,
+		opts:       r.opts,
+		headers:    append(append([]gzip.Header(nil), r.headers...), gzr.Header),
+		file:       r.file,
+		src:        r.src,
+	}
+	nr.rd = bufio.NewReader(gzr)
+
+	return nr, func() { gzr.Close(); r.file.Close() }, true
+}
+
 // BytePos returns the current position of the reader in the file or stream. Or alternatively,
 // returns the number of bytes that have been read.
 func (r *Reader) Offset() int {
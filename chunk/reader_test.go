@@ -0,0 +1,42 @@
+/*
+ * Copyright 2019 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package chunk
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestNewReaderDefaultGluedHeadersCoverEveryMember guards against chunk0-2 regressing to
+// recording only the first member's header once Workers is pinned to 1 (the non-pargzip path).
+func TestNewReaderDefaultGluedHeadersCoverEveryMember(t *testing.T) {
+	dir := t.TempDir()
+	var data []byte
+	data = append(data, gzipMember(t, "shard-0.rdf", "first\n")...)
+	data = append(data, gzipMember(t, "shard-1.rdf", "second\n")...)
+	path := writeFile(t, dir, "shards.gz", data)
+
+	rd, cleanup := NewReaderWithOptions(path, ReaderOptions{Workers: 1, BlockSize: 4096})
+	defer cleanup()
+
+	got, err := ioutil.ReadAll(rd.rd)
+	require.NoError(t, err)
+	require.Equal(t, "first\nsecond\n", string(got))
+	require.Equal(t, []string{"shard-0.rdf", "shard-1.rdf"}, headerNames(rd.Headers()))
+}
@@ -0,0 +1,196 @@
+/*
+ * Copyright 2019 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package chunk
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+
+	"github.com/dgraph-io/dgraph/x"
+)
+
+// urlRetries is how many times a dropped connection mid-stream is retried, resuming with a Range
+// request from the last byte offset successfully read, before the read is given up as failed.
+const urlRetries = 3
+
+// openURL dispatches on scheme to the right source opener and returns a ReadCloser streaming the
+// object's body, plus a synthetic filename (the URL's path) used only so the existing
+// extension-based gzip/codec sniffing in newReader has something to match against.
+func openURL(rawurl string) (io.ReadCloser, string) {
+	u, err := url.Parse(rawurl)
+	x.Check(err)
+
+	switch u.Scheme {
+	case "http", "https":
+		return newHTTPSource(rawurl), u.Path
+	case "s3":
+		return newS3Source(u), u.Path
+	case "gs":
+		return newGCSSource(u), u.Path
+	default:
+		x.Fatalf("chunk: unsupported URL scheme %q in %q", u.Scheme, rawurl)
+		return nil, ""
+	}
+}
+
+// isURL reports whether file names a remote object rather than a local path or "-" for stdin.
+func isURL(file string) bool {
+	for _, scheme := range []string{"http://", "https://", "s3://", "gs://"} {
+		if strings.HasPrefix(file, scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// retryReader wraps a factory that (re-)opens a ranged, resumable byte stream starting at a given
+// offset. On a read error it reopens the stream at the offset it had reached and keeps going,
+// rather than forcing the caller to restart a multi-hour ingest from byte zero.
+type retryReader struct {
+	open    func(offset int64) (io.ReadCloser, error)
+	rd      io.ReadCloser
+	offset  int64
+	retries int
+}
+
+func newRetryReader(open func(offset int64) (io.ReadCloser, error)) *retryReader {
+	return &retryReader{open: open}
+}
+
+// reopen calls r.open at the current offset, retrying with backoff up to urlRetries times if the
+// reconnect itself fails transiently (DNS blip, TLS handshake, a 5xx) rather than surfacing the
+// first failure to the caller -- a dropped connection is exactly when a reopen is most likely to
+// need a couple of attempts.
+func (r *retryReader) reopen() error {
+	var err error
+	for attempt := 0; ; attempt++ {
+		var rd io.ReadCloser
+		if rd, err = r.open(r.offset); err == nil {
+			r.rd = rd
+			return nil
+		}
+		if attempt >= urlRetries {
+			return err
+		}
+		time.Sleep(time.Second * time.Duration(attempt+1))
+	}
+}
+
+func (r *retryReader) Read(p []byte) (int, error) {
+	if r.rd == nil {
+		if err := r.reopen(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.rd.Read(p)
+	r.offset += int64(n)
+	if err != nil && err != io.EOF {
+		r.rd.Close()
+		r.rd = nil
+		if r.retries >= urlRetries {
+			return n, err
+		}
+		r.retries++
+		time.Sleep(time.Second * time.Duration(r.retries))
+		return n, nil
+	}
+	if err == nil {
+		r.retries = 0
+	}
+	return n, err
+}
+
+func (r *retryReader) Close() error {
+	if r.rd == nil {
+		return nil
+	}
+	return r.rd.Close()
+}
+
+// newHTTPSource streams rawurl's body, resuming with a "Range: bytes=N-" request if the
+// connection drops partway through.
+func newHTTPSource(rawurl string) io.ReadCloser {
+	return newRetryReader(func(offset int64) (io.ReadCloser, error) {
+		req, err := http.NewRequest(http.MethodGet, rawurl, nil)
+		if err != nil {
+			return nil, err
+		}
+		if offset > 0 {
+			req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+			resp.Body.Close()
+			return nil, x.Errorf("chunk: GET %s: unexpected status %s", rawurl, resp.Status)
+		}
+		return resp.Body, nil
+	})
+}
+
+// newS3Source streams an s3://bucket/key object's body via a ranged GetObject, resuming from the
+// last byte read on a transient error.
+func newS3Source(u *url.URL) io.ReadCloser {
+	sess := session.Must(session.NewSession())
+	svc := s3.New(sess)
+	bucket, key := u.Host, strings.TrimPrefix(u.Path, "/")
+
+	return newRetryReader(func(offset int64) (io.ReadCloser, error) {
+		in := &s3.GetObjectInput{
+			Bucket: aws.String(bucket),
+			Key:    aws.String(key),
+		}
+		if offset > 0 {
+			in.Range = aws.String(fmt.Sprintf("bytes=%d-", offset))
+		}
+		out, err := svc.GetObject(in)
+		if err != nil {
+			return nil, err
+		}
+		return out.Body, nil
+	})
+}
+
+// newGCSSource streams a gs://bucket/object object's body, resuming from the last byte read on a
+// transient error via a ranged object read.
+func newGCSSource(u *url.URL) io.ReadCloser {
+	ctx := context.Background()
+	client, err := storage.NewClient(ctx)
+	x.Check(err)
+	bucket, name := u.Host, strings.TrimPrefix(u.Path, "/")
+
+	return newRetryReader(func(offset int64) (io.ReadCloser, error) {
+		rd, err := client.Bucket(bucket).Object(name).NewRangeReader(ctx, offset, -1)
+		if err != nil {
+			return nil, err
+		}
+		return rd, nil
+	})
+}
@@ -0,0 +1,97 @@
+/*
+ * Copyright 2019 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package chunk
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// nopReadCloser is a minimal io.ReadCloser over a string body, used to stand in for an HTTP/S3/GCS
+// response body in these tests.
+type nopReadCloser struct{ io.Reader }
+
+func (nopReadCloser) Close() error { return nil }
+
+func TestRetryReaderSucceedsAfterTransientOpenFailure(t *testing.T) {
+	const body = "hello, indexed world"
+	attempts := 0
+
+	rr := newRetryReader(func(offset int64) (io.ReadCloser, error) {
+		attempts++
+		if attempts < 3 {
+			return nil, errors.New("transient: connection reset")
+		}
+		return nopReadCloser{strings.NewReader(body[offset:])}, nil
+	})
+
+	got, err := ioutil.ReadAll(rr)
+	require.NoError(t, err)
+	require.Equal(t, body, string(got))
+	require.GreaterOrEqual(t, attempts, 3)
+}
+
+func TestRetryReaderGivesUpAfterExhaustingRetries(t *testing.T) {
+	rr := newRetryReader(func(offset int64) (io.ReadCloser, error) {
+		return nil, errors.New("permanently unreachable")
+	})
+
+	_, err := ioutil.ReadAll(rr)
+	require.Error(t, err)
+}
+
+// flakyOnceReader serves body but fails exactly once, when failAt bytes have been read.
+type flakyOnceReader struct {
+	body   string
+	failAt int
+	read   int
+	failed bool
+}
+
+func (f *flakyOnceReader) Read(p []byte) (int, error) {
+	if f.read >= f.failAt && !f.failed {
+		f.failed = true
+		return 0, errors.New("connection reset mid-stream")
+	}
+	if f.read >= len(f.body) {
+		return 0, io.EOF
+	}
+	// Dole out one byte at a time so a large caller buffer can't swallow the whole body (and
+	// the injected failure) in a single Read call.
+	n := copy(p[:1], f.body[f.read:])
+	f.read += n
+	return n, nil
+}
+
+// TestRetryReaderResumesAtLastOffsetAfterMidStreamError guards the resumable-Range behavior: a
+// read that fails partway through should reopen at the offset already consumed, not from zero.
+func TestRetryReaderResumesAtLastOffsetAfterMidStreamError(t *testing.T) {
+	const body = "0123456789abcdefghij"
+
+	rr := newRetryReader(func(offset int64) (io.ReadCloser, error) {
+		return nopReadCloser{&flakyOnceReader{body: body[offset:], failAt: 5}}, nil
+	})
+
+	got, err := ioutil.ReadAll(rr)
+	require.NoError(t, err)
+	require.Equal(t, body, string(got))
+}